@@ -0,0 +1,91 @@
+// Command inbucket runs the SMTP/LMTP/POP3/HTTP servers that make up a single
+// inbucket instance.
+package main
+
+import (
+	"context"
+	"flag"
+	"net/http"
+
+	"github.com/inbucket/inbucket/v3/pkg/config"
+	"github.com/inbucket/inbucket/v3/pkg/extension"
+	"github.com/inbucket/inbucket/v3/pkg/message"
+	"github.com/inbucket/inbucket/v3/pkg/policy"
+	"github.com/inbucket/inbucket/v3/pkg/rest/web"
+	"github.com/inbucket/inbucket/v3/pkg/server/smtp"
+	"github.com/rs/zerolog/log"
+)
+
+func main() {
+	configPath := flag.String("config", "", "path to inbucket configuration file")
+	flag.Parse()
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to load configuration")
+	}
+
+	manager, err := message.NewStore(cfg)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to open message store")
+	}
+	addrPolicy := policy.NewAddressing(cfg)
+	extHost := extension.NewHost()
+
+	smtpServer := smtp.NewServer(cfg.SMTP, manager, addrPolicy, extHost)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ready := make(chan struct{})
+	go smtpServer.Start(ctx, func() { close(ready) })
+
+	// Exposes the nginx ngx_mail_core_module auth_http endpoint so nginx can
+	// terminate mail protocol TLS itself and ask inbucket whether to proceed.
+	mux := http.NewServeMux()
+	mux.Handle("/nginx-mail-auth", web.NewNginxMailAuthHandler(addrPolicy, cfg.SMTP.Addr, nil, nil))
+	httpServer := &http.Server{Addr: cfg.Web.Addr, Handler: mux}
+	go func() {
+		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Error().Err(err).Msg("HTTP server failed")
+		}
+	}()
+
+	// drain waits for in-flight SMTP sessions to finish, then gracefully stops the
+	// nginx-mail-auth HTTP server so a clean shutdown doesn't leave it listening
+	// after the process claims to have drained.
+	drain := func() {
+		smtpServer.Drain()
+		if err := httpServer.Shutdown(context.Background()); err != nil {
+			log.Error().Err(err).Msg("Failed to shut down nginx-mail-auth HTTP server")
+		}
+	}
+
+	// Server.Start binds its listener under the "lmtp" key instead of "smtp"
+	// whenever LMTP mode is enabled; the fd must be inherited under the same key
+	// the child's listen() call will look it up by, or the re-exec'd child binds a
+	// fresh listener instead of taking over the parent's.
+	smtpListenerKey := "smtp"
+	if cfg.SMTP.LMTP {
+		smtpListenerKey = "lmtp"
+	}
+
+	// SIGUSR2 forks a replacement for zero-downtime restart, SIGHUP additionally
+	// reloads TLS certs first, and SIGTERM/SIGINT drain in-flight sessions before
+	// exit. See handleRestartSignals in restart.go for the full contract.
+	shutdown := handleRestartSignals(
+		cancel,
+		drain,
+		[]namedListener{{key: smtpListenerKey, listenerFiler: smtpServer}},
+		smtpServer,
+	)
+
+	<-ready
+	// A clean shutdown closes shutdown once sessions have drained rather than
+	// sending to Notify(), which is reserved for fatal, unrecoverable errors.
+	select {
+	case err := <-smtpServer.Notify():
+		if err != nil {
+			log.Fatal().Err(err).Msg("SMTP server exited")
+		}
+	case <-shutdown:
+	}
+}
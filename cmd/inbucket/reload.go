@@ -0,0 +1,28 @@
+package main
+
+import (
+	"github.com/rs/zerolog/log"
+)
+
+// tlsReloader is satisfied by smtp.Server and, eventually, pop3.Server; each
+// re-reads its configured certificate/key pair from disk and swaps it in without
+// dropping active connections. The nginx-mail-auth HTTP server doesn't terminate
+// its own TLS (nginx does, per the auth_http contract), so there's nothing for it
+// to reload here.
+type tlsReloader interface {
+	ReloadTLS() error
+}
+
+// reloadAll calls ReloadTLS on every reloader, logging (but not aborting on) any
+// individual failure. It's invoked as part of handleRestartSignals' SIGHUP case in
+// restart.go rather than from its own signal handler, since SIGHUP also triggers a
+// fork+exec restart there and a re-exec'd child reads fresh certificates from disk
+// on its own anyway; a second, independent SIGHUP handler here would race it.
+func reloadAll(reloaders ...tlsReloader) {
+	slog := log.With().Str("module", "main").Str("phase", "reload").Logger()
+	for _, r := range reloaders {
+		if err := r.ReloadTLS(); err != nil {
+			slog.Error().Err(err).Msg("Failed to reload TLS certificate")
+		}
+	}
+}
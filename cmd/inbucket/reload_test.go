@@ -0,0 +1,38 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+type fakeReloader struct {
+	err    error
+	called bool
+}
+
+func (f *fakeReloader) ReloadTLS() error {
+	f.called = true
+	return f.err
+}
+
+func TestReloadAllCallsEveryReloader(t *testing.T) {
+	a := &fakeReloader{}
+	b := &fakeReloader{}
+
+	reloadAll(a, b)
+
+	if !a.called || !b.called {
+		t.Errorf("reloadAll() called = (%v, %v), want (true, true)", a.called, b.called)
+	}
+}
+
+func TestReloadAllContinuesPastFailure(t *testing.T) {
+	failing := &fakeReloader{err: errors.New("bad cert")}
+	following := &fakeReloader{}
+
+	reloadAll(failing, following)
+
+	if !following.called {
+		t.Error("reloadAll() stopped after an earlier reloader failed, want it to continue")
+	}
+}
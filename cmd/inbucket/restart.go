@@ -0,0 +1,132 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/rs/zerolog/log"
+)
+
+// listenerFiler is satisfied by smtp.Server and, eventually, pop3.Server; it
+// exposes the underlying listener fd so it can be inherited across a fork+exec
+// restart via ExtraFiles. The nginx-mail-auth HTTP server doesn't implement this
+// (or tlsReloader in reload.go) — its listener fd isn't extracted for inheritance, so it
+// only participates in the drain half of a restart; see main.go.
+type listenerFiler interface {
+	ListenerFile() (*os.File, error)
+}
+
+// namedListener pairs a listenerFiler with the key its owner will look it up by
+// in INBUCKET_LISTEN_FDS (e.g. "smtp", "lmtp", "http"), so a child process with
+// several listeners inherits each one from the fd its parent assigned it rather
+// than guessing from ExtraFiles ordering alone.
+type namedListener struct {
+	key string
+	listenerFiler
+}
+
+// drainer is satisfied by smtp.Server and its siblings; Drain blocks until all
+// in-flight sessions have finished.
+type drainer interface {
+	Drain()
+}
+
+// handleRestartSignals wires up zero-downtime restart and graceful shutdown:
+//
+//   - SIGUSR2 forks and re-execs the current binary, handing it the listener fds
+//     so it can pick up where the parent left off without a gap in service.
+//   - SIGTERM/SIGINT stop accepting new connections and drain in-flight sessions
+//     before the process exits.
+//   - SIGHUP does all three: reload TLS certificates, fork a replacement, then
+//     drain the parent so in-flight DATA transactions complete under the old
+//     process while new connections are already landing on the child. The
+//     reload is mostly a courtesy for operators watching the reload log line,
+//     since the forked child re-reads certificates from disk on its own.
+//
+// cancel stops the servers from accepting new connections (e.g. by closing their
+// listeners); drain blocks until existing sessions finish. This is the only
+// SIGHUP handler in the process — do not register another one for TLS reload
+// alone, or the two will race over who gets to the signal first.
+//
+// The returned channel is closed once cancel+drain have completed for a SIGHUP
+// or SIGTERM/SIGINT shutdown, so callers that are otherwise only watching a
+// server's Notify() for a fatal error have a way to learn that a clean shutdown
+// finished and it's safe to exit.
+func handleRestartSignals(cancel func(), drain func(), listeners []namedListener, reloaders ...tlsReloader) <-chan struct{} {
+	slog := log.With().Str("module", "main").Str("phase", "restart").Logger()
+	sigCh := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(sigCh, syscall.SIGUSR2, syscall.SIGTERM, syscall.SIGINT, syscall.SIGHUP)
+	go func() {
+		for sig := range sigCh {
+			switch sig {
+			case syscall.SIGUSR2:
+				slog.Info().Msg("SIGUSR2 received, forking replacement process")
+				if err := forkExec(listeners...); err != nil {
+					slog.Error().Err(err).Msg("Failed to fork replacement process")
+				}
+			case syscall.SIGHUP:
+				slog.Info().Msg("SIGHUP received, reloading TLS, forking replacement, and draining")
+				reloadAll(reloaders...)
+				if err := forkExec(listeners...); err != nil {
+					slog.Error().Err(err).Msg("Failed to fork replacement process")
+				}
+				cancel()
+				drain()
+				close(done)
+				return
+			case syscall.SIGTERM, syscall.SIGINT:
+				slog.Info().Msg("Shutdown signal received, draining active sessions")
+				cancel()
+				drain()
+				close(done)
+				return
+			}
+		}
+	}()
+	return done
+}
+
+// forkExec re-execs the current binary, passing each listener's file descriptor via
+// ExtraFiles (which always land at fd 3, 4, 5, ... in the child, in order) and
+// recording each one's key=fd pair in INBUCKET_LISTEN_FDS so every server looks up
+// its own descriptor by key instead of assuming a fixed position.
+func forkExec(listeners ...namedListener) error {
+	files := make([]*os.File, 0, len(listeners))
+	pairs := make([]string, 0, len(listeners))
+	for i, l := range listeners {
+		f, err := l.ListenerFile()
+		if err != nil {
+			for _, opened := range files {
+				opened.Close()
+			}
+			return err
+		}
+		files = append(files, f)
+		pairs = append(pairs, l.key+"="+strconv.Itoa(3+i))
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(exe, os.Args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(), "INBUCKET_LISTEN_FDS="+strings.Join(pairs, ","))
+	cmd.ExtraFiles = files
+
+	// cmd.Start dups each file for the child; the parent's copies are only needed
+	// until then, and are closed here (on both success and failure) so a restart
+	// doesn't leak one fd per listener.
+	startErr := cmd.Start()
+	for _, f := range files {
+		f.Close()
+	}
+	return startErr
+}
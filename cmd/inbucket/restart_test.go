@@ -0,0 +1,68 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+type fakeListenerFiler struct {
+	file *os.File
+	err  error
+}
+
+func (f fakeListenerFiler) ListenerFile() (*os.File, error) {
+	return f.file, f.err
+}
+
+func TestForkExecClosesOpenedFilesOnLaterError(t *testing.T) {
+	tmp, err := os.CreateTemp(t.TempDir(), "inbucket-restart-test")
+	if err != nil {
+		t.Fatalf("failed creating temp file: %v", err)
+	}
+
+	listeners := []namedListener{
+		{key: "smtp", listenerFiler: fakeListenerFiler{file: tmp}},
+		{key: "lmtp", listenerFiler: fakeListenerFiler{err: errors.New("no fd for you")}},
+	}
+
+	if err := forkExec(listeners...); err == nil {
+		t.Fatal("forkExec() error = nil, want the second listener's error")
+	}
+
+	if _, err := tmp.Write([]byte("x")); err == nil {
+		t.Error("forkExec() left the first listener's file open after a later one failed")
+	}
+}
+
+func TestHandleRestartSignalsDrainsOnSigterm(t *testing.T) {
+	cancelled := make(chan struct{})
+	drained := make(chan struct{})
+	cancel := func() { close(cancelled) }
+	drain := func() { close(drained) }
+
+	done := handleRestartSignals(cancel, drain, nil)
+
+	if err := syscall.Kill(os.Getpid(), syscall.SIGTERM); err != nil {
+		t.Fatalf("failed sending SIGTERM: %v", err)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("handleRestartSignals did not close its done channel after SIGTERM")
+	}
+
+	select {
+	case <-cancelled:
+	default:
+		t.Error("cancel was not called before done closed")
+	}
+	select {
+	case <-drained:
+	default:
+		t.Error("drain was not called before done closed")
+	}
+}
@@ -0,0 +1,65 @@
+package client
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// Client is a minimal REST client for inbucket's v1 HTTP API, configured via
+// the functional Option pattern in apiv1_client_opts.go.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// New creates a Client for the inbucket instance at baseURL, applying any
+// supplied options over the defaults from getDefaultOptions.
+func New(baseURL string, opts ...Option) *Client {
+	o := getDefaultOptions()
+	for _, opt := range opts {
+		opt.apply(o)
+	}
+
+	transport := o.transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	return &Client{
+		baseURL: baseURL,
+		httpClient: &http.Client{
+			Timeout:   o.timeout,
+			Transport: &loggingRoundTripper{next: transport, logger: o.logger},
+		},
+	}
+}
+
+// Do sends req using the client's configured transport, logging the outcome
+// through the configured logger before returning the response.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	return c.httpClient.Do(req)
+}
+
+// loggingRoundTripper wraps another http.RoundTripper, emitting a debug log
+// line for every request/response pair (or an error log on transport failure)
+// through the logger configured via WithLogger.
+type loggingRoundTripper struct {
+	next   http.RoundTripper
+	logger *slog.Logger
+}
+
+func (rt *loggingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := rt.next.RoundTrip(req)
+	if err != nil {
+		rt.logger.ErrorContext(req.Context(), "rest request failed",
+			"method", req.Method, "url", req.URL.String(), "error", err)
+		return nil, fmt.Errorf("rest client: %w", err)
+	}
+	rt.logger.DebugContext(req.Context(), "rest request completed",
+		"method", req.Method, "url", req.URL.String(),
+		"status", resp.StatusCode, "duration", time.Since(start))
+	return resp, nil
+}
@@ -1,7 +1,9 @@
 package client
 
 import (
+	"log/slog"
 	"net/http"
+	"os"
 	"time"
 )
 
@@ -9,6 +11,7 @@ import (
 type options struct {
 	transport http.RoundTripper
 	timeout   time.Duration
+	logger    *slog.Logger
 }
 
 type Option interface {
@@ -18,6 +21,7 @@ type Option interface {
 func getDefaultOptions() *options {
 	return &options{
 		timeout: 30 * time.Second,
+		logger:  slog.New(slog.NewTextHandler(os.Stderr, nil)),
 	}
 }
 
@@ -36,3 +40,17 @@ func (t transportOption) apply(opts *options) {
 func WithOptTransport(transport http.RoundTripper) Option {
 	return transportOption{transport}
 }
+
+type loggerOption struct {
+	logger *slog.Logger
+}
+
+func (l loggerOption) apply(opts *options) {
+	opts.logger = l.logger
+}
+
+// WithLogger sets the logger used for the rest client's request/response
+// diagnostics. Without this option a slog.TextHandler writing to os.Stderr is used.
+func WithLogger(logger *slog.Logger) Option {
+	return loggerOption{logger}
+}
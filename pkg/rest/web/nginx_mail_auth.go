@@ -0,0 +1,93 @@
+// Package web holds HTTP handlers that exist to serve other mail infrastructure
+// rather than the inbucket web UI/API itself.
+package web
+
+import (
+	"log/slog"
+	"net"
+	"net/http"
+
+	"github.com/inbucket/inbucket/v3/pkg/policy"
+)
+
+// AuthChecker validates a username/password pair submitted to the nginx mail auth
+// endpoint. The zero value behavior callers get via NewNginxMailAuthHandler is
+// acceptAllChecker, matching inbucket's existing no-auth-required posture;
+// deployments that front inbucket with real credentials (e.g. an htpasswd file)
+// supply their own implementation.
+type AuthChecker interface {
+	CheckAuth(protocol, user, pass string) bool
+}
+
+// acceptAllChecker authorizes every login, preserving inbucket's current
+// behavior of accepting any sender.
+type acceptAllChecker struct{}
+
+func (acceptAllChecker) CheckAuth(protocol, user, pass string) bool {
+	return true
+}
+
+// NginxMailAuthHandler implements the HTTP auth endpoint consumed by nginx's
+// ngx_mail_core_module auth_http directive: nginx terminates SMTP/POP3/IMAP TLS
+// itself and asks this endpoint, via request headers, whether to proceed and
+// which backend to proxy to.
+type NginxMailAuthHandler struct {
+	addrPolicy *policy.Addressing
+	smtpAddr   string // host:port inbucket's own SMTP listener is bound to.
+	checker    AuthChecker
+	logger     *slog.Logger
+}
+
+// NewNginxMailAuthHandler builds a handler that checks SMTP senders against
+// addrPolicy and every login (SMTP included) against checker, pointing successful logins at
+// smtpAddr. checker may be nil, in which case every login is accepted, matching
+// inbucket's current behavior. logger may be nil, in which case slog.Default() is
+// used.
+func NewNginxMailAuthHandler(addrPolicy *policy.Addressing, smtpAddr string, checker AuthChecker, logger *slog.Logger) *NginxMailAuthHandler {
+	if checker == nil {
+		checker = acceptAllChecker{}
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &NginxMailAuthHandler{addrPolicy: addrPolicy, smtpAddr: smtpAddr, checker: checker, logger: logger}
+}
+
+// ServeHTTP answers nginx's auth_http request. See
+// https://nginx.org/en/docs/mail/ngx_mail_auth_http_module.html for the header
+// contract this implements.
+func (h *NginxMailAuthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	protocol := r.Header.Get("Auth-Protocol")
+	user := r.Header.Get("Auth-User")
+	pass := r.Header.Get("Auth-Pass")
+	clientIP := r.Header.Get("Client-IP")
+	clientHost := r.Header.Get("Client-Host")
+
+	h.logger.Debug("nginx mail auth request",
+		"protocol", protocol, "user", user, "client_ip", clientIP, "client_host", clientHost)
+
+	if protocol == "smtp" {
+		if _, err := h.addrPolicy.ExtractMailbox(user); err != nil {
+			w.Header().Set("Auth-Status", "Invalid login")
+			w.Header().Set("Auth-Wait", "0")
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+	}
+
+	if !h.checker.CheckAuth(protocol, user, pass) {
+		w.Header().Set("Auth-Status", "Invalid login")
+		w.Header().Set("Auth-Wait", "0")
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	host, port, err := net.SplitHostPort(h.smtpAddr)
+	if err != nil {
+		host, port = h.smtpAddr, "25"
+	}
+	w.Header().Set("Auth-Status", "OK")
+	w.Header().Set("Auth-Server", host)
+	w.Header().Set("Auth-Port", port)
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,58 @@
+package web
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type stubChecker struct{ allow bool }
+
+func (s stubChecker) CheckAuth(protocol, user, pass string) bool {
+	return s.allow
+}
+
+func TestServeHTTPLogsClientIPAndHost(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	h := NewNginxMailAuthHandler(nil, "127.0.0.1:2500", stubChecker{allow: true}, logger)
+
+	req := httptest.NewRequest(http.MethodGet, "/nginx-mail-auth", nil)
+	req.Header.Set("Auth-Protocol", "pop3")
+	req.Header.Set("Auth-User", "someone")
+	req.Header.Set("Client-IP", "203.0.113.5")
+	req.Header.Set("Client-Host", "mail.example.com")
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("Auth-Status"); got != "OK" {
+		t.Fatalf("Auth-Status = %q, want OK", got)
+	}
+
+	logged := buf.String()
+	if !strings.Contains(logged, "203.0.113.5") {
+		t.Errorf("log output missing Client-IP value, got: %s", logged)
+	}
+	if !strings.Contains(logged, "mail.example.com") {
+		t.Errorf("log output missing Client-Host value, got: %s", logged)
+	}
+}
+
+func TestServeHTTPRejectsFailedAuth(t *testing.T) {
+	h := NewNginxMailAuthHandler(nil, "127.0.0.1:2500", stubChecker{allow: false}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/nginx-mail-auth", nil)
+	req.Header.Set("Auth-Protocol", "pop3")
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("Auth-Status"); got != "Invalid login" {
+		t.Fatalf("Auth-Status = %q, want Invalid login", got)
+	}
+}
@@ -0,0 +1,113 @@
+package smtp
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// envListenFDs names the environment variable a re-exec'd parent sets so the child
+// can reconstruct its listeners from inherited file descriptors instead of binding
+// fresh sockets. Its value is a comma-separated list of "key=fd" pairs, e.g.
+// "smtp=3,lmtp=4", so each listener can pick out the descriptor assigned to it
+// rather than assuming a fixed position. Set by the restart orchestration in
+// cmd/inbucket.
+const envListenFDs = "INBUCKET_LISTEN_FDS"
+
+// inheritedFD looks up the fd assigned to key in envListenFDs, returning ok=false
+// if the variable is unset or has no entry for key.
+func inheritedFD(key string) (fd int, ok bool) {
+	fdList := os.Getenv(envListenFDs)
+	if fdList == "" {
+		return 0, false
+	}
+	for _, pair := range strings.Split(fdList, ",") {
+		k, v, found := strings.Cut(pair, "=")
+		if !found || k != key {
+			continue
+		}
+		fd, err := strconv.Atoi(v)
+		if err != nil {
+			return 0, false
+		}
+		return fd, true
+	}
+	return 0, false
+}
+
+// listen returns a net.Listener for addr, reconstructing it from the fd assigned
+// to key in envListenFDs when present (populated by a parent process performing a
+// fork+exec graceful restart), or binding a fresh listener otherwise. key
+// identifies which listener this is (e.g. "smtp", "lmtp") so a process with
+// several listeners inherits each from its own fd instead of all racing for the
+// same one. network selects the socket family: "tcp" (dual-stack, the default),
+// "tcp4", "tcp6", or "unix". When forceTLS is set the returned listener wraps the
+// connection in TLS.
+//
+// listen also returns the pre-TLS-wrap listener (raw) so a caller extracting the
+// underlying fd for inheritance, e.g. Server.ListenerFile, doesn't have to see
+// through the *tls.listener wrapper, which doesn't promote the File() method of
+// the net.Listener it embeds as an interface. raw is the same value as the
+// returned listener whenever forceTLS is false.
+func listen(key, network, addr string, forceTLS bool, tlsConfig *tls.Config) (listener, raw net.Listener, err error) {
+	if network == "" {
+		network = "tcp"
+	}
+	if fd, ok := inheritedFD(key); ok {
+		f := os.NewFile(uintptr(fd), key+"-inherited-listener")
+		raw, err = net.FileListener(f)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to inherit %s listener fd %d: %w", key, fd, err)
+		}
+		// The net.Listener holds its own duplicate of the fd.
+		f.Close()
+		if forceTLS {
+			return tls.NewListener(raw, tlsConfig), raw, nil
+		}
+		return raw, raw, nil
+	}
+
+	if network == "unix" {
+		raw, err = net.Listen("unix", addr)
+		if err != nil {
+			return nil, nil, err
+		}
+		if forceTLS {
+			return tls.NewListener(raw, tlsConfig), raw, nil
+		}
+		return raw, raw, nil
+	}
+
+	tcpAddr, err := net.ResolveTCPAddr(network, addr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to build %s address: %w", network, err)
+	}
+	raw, err = net.ListenTCP(network, tcpAddr)
+	if err != nil {
+		return nil, nil, err
+	}
+	if forceTLS {
+		return tls.NewListener(raw, tlsConfig), raw, nil
+	}
+	return raw, raw, nil
+}
+
+// ListenerFile returns the underlying *os.File for the server's listener so a
+// parent process can pass its descriptor to a re-exec'd child via ExtraFiles as
+// part of a graceful restart. It extracts the fd from the pre-TLS-wrap listener,
+// since *tls.listener never exposes File() itself, so this works whether or not
+// config.ForceTLS is set. Returns an error if the underlying listener doesn't
+// support file extraction (e.g. an inherited unix socket listener on a platform
+// without File() support).
+func (s *Server) ListenerFile() (*os.File, error) {
+	type filer interface {
+		File() (*os.File, error)
+	}
+	if f, ok := s.rawListener.(filer); ok {
+		return f.File()
+	}
+	return nil, fmt.Errorf("smtp: listener of type %T does not support fd extraction", s.rawListener)
+}
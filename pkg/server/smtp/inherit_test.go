@@ -0,0 +1,43 @@
+package smtp
+
+import (
+	"crypto/tls"
+	"testing"
+)
+
+func TestInheritedFD(t *testing.T) {
+	t.Setenv(envListenFDs, "smtp=3,lmtp=4")
+
+	if fd, ok := inheritedFD("smtp"); !ok || fd != 3 {
+		t.Errorf("inheritedFD(smtp) = (%d, %v), want (3, true)", fd, ok)
+	}
+	if fd, ok := inheritedFD("lmtp"); !ok || fd != 4 {
+		t.Errorf("inheritedFD(lmtp) = (%d, %v), want (4, true)", fd, ok)
+	}
+	if _, ok := inheritedFD("http"); ok {
+		t.Errorf("inheritedFD(http) = ok, want not found")
+	}
+}
+
+func TestInheritedFDUnset(t *testing.T) {
+	t.Setenv(envListenFDs, "")
+
+	if _, ok := inheritedFD("smtp"); ok {
+		t.Errorf("inheritedFD(smtp) with unset env = ok, want not found")
+	}
+}
+
+func TestListenerFileForceTLS(t *testing.T) {
+	listener, raw, err := listen("test-forcetls", "tcp", "127.0.0.1:0", true, &tls.Config{})
+	if err != nil {
+		t.Fatalf("listen() error = %v", err)
+	}
+	defer listener.Close()
+
+	s := &Server{listener: listener, rawListener: raw}
+	f, err := s.ListenerFile()
+	if err != nil {
+		t.Fatalf("ListenerFile() with ForceTLS error = %v, want nil", err)
+	}
+	f.Close()
+}
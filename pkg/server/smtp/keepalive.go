@@ -0,0 +1,50 @@
+package smtp
+
+import (
+	"crypto/tls"
+	"net"
+)
+
+// tcpConnOf unwraps conn down to its underlying *net.TCPConn, if any, so
+// applyKeepalive can tune a connection even when it arrives wrapped in a
+// *tls.Conn (implicit TLS or a completed STARTTLS) or a proxyConn (from
+// readProxyHeader). Returns false if conn isn't backed by a TCP connection,
+// e.g. a unix domain socket.
+func tcpConnOf(conn net.Conn) (*net.TCPConn, bool) {
+	for {
+		switch c := conn.(type) {
+		case *net.TCPConn:
+			return c, true
+		case *tls.Conn:
+			conn = c.NetConn()
+		case *proxyConn:
+			conn = c.Conn
+		case *bufferedConn:
+			conn = c.Conn
+		case interface{ NetConn() net.Conn }:
+			conn = c.NetConn()
+		default:
+			return nil, false
+		}
+	}
+}
+
+// applyKeepalive enables TCP keepalive on conn using the server's configured
+// period, and additionally tunes the probe count/interval where the platform
+// supports it (see keepalive_linux.go). A zero KeepAlivePeriod leaves the
+// connection at the OS default, since most operators never need to touch this.
+func (s *Server) applyKeepalive(conn *net.TCPConn) error {
+	if s.config.KeepAlivePeriod <= 0 {
+		return nil
+	}
+	if err := conn.SetKeepAlive(true); err != nil {
+		return err
+	}
+	if err := conn.SetKeepAlivePeriod(s.config.KeepAlivePeriod); err != nil {
+		return err
+	}
+	if s.config.KeepAliveProbes > 0 || s.config.KeepAliveInterval > 0 {
+		return tuneKeepalive(conn, s.config.KeepAliveProbes, s.config.KeepAliveInterval)
+	}
+	return nil
+}
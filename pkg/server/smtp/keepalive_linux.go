@@ -0,0 +1,41 @@
+//go:build linux
+
+package smtp
+
+import (
+	"net"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// tuneKeepalive sets TCP_KEEPCNT and TCP_KEEPINTVL directly via the socket's raw
+// connection, since the net package only exposes the keepalive period (TCP_KEEPIDLE)
+// in a portable way. probes of 0 or interval of 0 leave that particular setting at
+// the OS default.
+func tuneKeepalive(conn *net.TCPConn, probes int, interval time.Duration) error {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var ctrlErr error
+	err = raw.Control(func(fd uintptr) {
+		if probes > 0 {
+			if e := unix.SetsockoptInt(int(fd), unix.IPPROTO_TCP, unix.TCP_KEEPCNT, probes); e != nil {
+				ctrlErr = e
+				return
+			}
+		}
+		if interval > 0 {
+			secs := int(interval.Round(time.Second) / time.Second)
+			if e := unix.SetsockoptInt(int(fd), unix.IPPROTO_TCP, unix.TCP_KEEPINTVL, secs); e != nil {
+				ctrlErr = e
+				return
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return ctrlErr
+}
@@ -0,0 +1,16 @@
+//go:build !linux
+
+package smtp
+
+import (
+	"net"
+	"time"
+)
+
+// tuneKeepalive is a no-op outside Linux: the net package has no portable way to
+// set TCP_KEEPCNT/TCP_KEEPINTVL, so KeepAliveProbes/KeepAliveInterval are only
+// honored on Linux. KeepAlivePeriod (TCP_KEEPIDLE) still applies everywhere via
+// net.TCPConn.SetKeepAlivePeriod in applyKeepalive.
+func tuneKeepalive(conn *net.TCPConn, probes int, interval time.Duration) error {
+	return nil
+}
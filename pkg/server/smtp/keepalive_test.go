@@ -0,0 +1,65 @@
+package smtp
+
+import (
+	"net"
+	"testing"
+)
+
+func dialLoopback(t *testing.T) (*net.TCPConn, func()) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	acceptErr := make(chan error, 1)
+	var serverConn net.Conn
+	go func() {
+		c, err := ln.Accept()
+		serverConn = c
+		acceptErr <- err
+	}()
+	clientConn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	if err := <-acceptErr; err != nil {
+		t.Fatalf("failed to accept: %v", err)
+	}
+	cleanup := func() {
+		clientConn.Close()
+		serverConn.Close()
+		ln.Close()
+	}
+	return serverConn.(*net.TCPConn), cleanup
+}
+
+func TestTCPConnOfDirect(t *testing.T) {
+	conn, cleanup := dialLoopback(t)
+	defer cleanup()
+
+	got, ok := tcpConnOf(conn)
+	if !ok || got != conn {
+		t.Fatalf("tcpConnOf(direct) = (%v, %v), want (%v, true)", got, ok, conn)
+	}
+}
+
+func TestTCPConnOfProxyWrapped(t *testing.T) {
+	conn, cleanup := dialLoopback(t)
+	defer cleanup()
+
+	wrapped := &proxyConn{Conn: &bufferedConn{Conn: conn}, remoteAddr: conn.RemoteAddr()}
+	got, ok := tcpConnOf(wrapped)
+	if !ok || got != conn {
+		t.Fatalf("tcpConnOf(proxy-wrapped) = (%v, %v), want (%v, true)", got, ok, conn)
+	}
+}
+
+func TestTCPConnOfNonTCP(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	if _, ok := tcpConnOf(server); ok {
+		t.Errorf("tcpConnOf(net.Pipe) = ok, want not-TCP")
+	}
+}
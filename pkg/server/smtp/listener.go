@@ -4,9 +4,14 @@ import (
 	"container/list"
 	"context"
 	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"expvar"
+	"fmt"
+	"log/slog"
 	"net"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/inbucket/inbucket/v3/pkg/config"
@@ -14,7 +19,11 @@ import (
 	"github.com/inbucket/inbucket/v3/pkg/message"
 	"github.com/inbucket/inbucket/v3/pkg/metric"
 	"github.com/inbucket/inbucket/v3/pkg/policy"
-	"github.com/rs/zerolog/log"
+)
+
+var (
+	errNoCertificate = errors.New("smtp: no TLS certificate loaded")
+	errTLSNotEnabled = errors.New("smtp: TLS is not enabled, nothing to reload")
 )
 
 var (
@@ -59,14 +68,17 @@ func init() {
 
 // Server holds the configuration and state of our SMTP server.
 type Server struct {
-	config     config.SMTP        // SMTP configuration.
-	tlsConfig  *tls.Config        // TLS encryption configuration.
-	addrPolicy *policy.Addressing // Address policy.
-	manager    message.Manager    // Used to deliver messages.
-	extHost    *extension.Host    // Extension event processor.
-	listener   net.Listener       // Incoming network connections.
-	wg         *sync.WaitGroup    // Waitgroup tracks individual sessions.
-	notify     chan error         // Notify on fatal error.
+	config      config.SMTP        // SMTP configuration.
+	tlsConfig   *tls.Config        // TLS encryption configuration.
+	tlsCert     atomic.Value       // Holds the current *tls.Certificate, swapped by ReloadTLS.
+	addrPolicy  *policy.Addressing // Address policy.
+	manager     message.Manager    // Used to deliver messages.
+	extHost     *extension.Host    // Extension event processor.
+	listener    net.Listener       // Incoming network connections.
+	rawListener net.Listener       // Pre-TLS-wrap listener, used for fd extraction in ListenerFile.
+	wg          *sync.WaitGroup    // Waitgroup tracks individual sessions.
+	notify      chan error         // Notify on fatal error.
+	logger      *slog.Logger       // Structured logger; defaults to a zerolog-backed handler.
 }
 
 // NewServer creates a new, unstarted, SMTP server instance with the specificed config.
@@ -75,23 +87,15 @@ func NewServer(
 	manager message.Manager,
 	apolicy *policy.Addressing,
 	extHost *extension.Host,
+	opts ...Option,
 ) *Server {
-	slog := log.With().Str("module", "smtp").Str("phase", "tls").Logger()
-	tlsConfig := &tls.Config{}
-	if smtpConfig.TLSEnabled {
-		var err error
-		tlsConfig.Certificates = make([]tls.Certificate, 1)
-		tlsConfig.Certificates[0], err = tls.LoadX509KeyPair(smtpConfig.TLSCert, smtpConfig.TLSPrivKey)
-		if err != nil {
-			slog.Error().Msgf("Failed loading X509 KeyPair: %v", err)
-			slog.Error().Msg("Disabling STARTTLS support")
-			smtpConfig.TLSEnabled = false
-		} else {
-			slog.Debug().Msg("STARTTLS feature available")
-		}
+	o := getDefaultOptions()
+	for _, opt := range opts {
+		opt.apply(o)
 	}
 
-	return &Server{
+	tlsConfig := &tls.Config{}
+	s := &Server{
 		config:     smtpConfig,
 		tlsConfig:  tlsConfig,
 		manager:    manager,
@@ -99,31 +103,116 @@ func NewServer(
 		extHost:    extHost,
 		wg:         new(sync.WaitGroup),
 		notify:     make(chan error, 1),
+		logger:     o.logger,
 	}
+	if smtpConfig.TLSEnabled {
+		cert, err := tls.LoadX509KeyPair(smtpConfig.TLSCert, smtpConfig.TLSPrivKey)
+		if err != nil {
+			s.logger.Error("failed loading X509 KeyPair", "error", err)
+			s.logger.Error("disabling STARTTLS support")
+			smtpConfig.TLSEnabled = false
+			s.config = smtpConfig
+		} else {
+			s.tlsCert.Store(&cert)
+			tlsConfig.GetCertificate = s.getCertificate
+			s.logger.Debug("STARTTLS feature available")
+		}
+	}
+
+	return s
 }
 
-// Start the listener and handle incoming connections.
-func (s *Server) Start(ctx context.Context, readyFunc func()) {
-	slog := log.With().Str("module", "smtp").Str("phase", "startup").Logger()
-	addr, err := net.ResolveTCPAddr("tcp4", s.config.Addr)
+// getCertificate backs tlsConfig.GetCertificate, always returning the most recently
+// loaded certificate so in-flight handshakes see a consistent pair even across a
+// concurrent ReloadTLS call.
+func (s *Server) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cert, _ := s.tlsCert.Load().(*tls.Certificate)
+	if cert == nil {
+		return nil, errNoCertificate
+	}
+	return cert, nil
+}
+
+// ReloadTLS re-reads the configured TLS certificate/key pair from disk and, if they
+// parse and validate, atomically swaps them in for future handshakes. Sessions that
+// have already completed STARTTLS or an implicit-TLS handshake are unaffected; only
+// new handshakes pick up the reloaded certificate. Safe to call from a signal handler
+// goroutine while the server is serving connections.
+func (s *Server) ReloadTLS() error {
+	ctx := context.Background()
+	if !s.config.TLSEnabled {
+		return errTLSNotEnabled
+	}
+	cert, err := tls.LoadX509KeyPair(s.config.TLSCert, s.config.TLSPrivKey)
 	if err != nil {
-		slog.Error().Err(err).Msg("Failed to build tcp4 address")
-		s.notify <- err
-		close(s.notify)
-		return
+		return fmt.Errorf("failed loading X509 KeyPair: %w", err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return fmt.Errorf("failed parsing reloaded certificate: %w", err)
+	}
+	cert.Leaf = leaf
+
+	var oldNotBefore, oldNotAfter time.Time
+	if old, ok := s.tlsCert.Load().(*tls.Certificate); ok && old != nil {
+		if oldLeaf, err := x509.ParseCertificate(old.Certificate[0]); err == nil {
+			oldNotBefore, oldNotAfter = oldLeaf.NotBefore, oldLeaf.NotAfter
+		}
 	}
-	slog.Info().Str("addr", addr.String()).Msg("SMTP listening on tcp4")
-	if s.config.ForceTLS {
-		s.listener, err = tls.Listen("tcp4", addr.String(), s.tlsConfig)
-	} else {
-		s.listener, err = net.ListenTCP("tcp4", addr)
+
+	s.tlsCert.Store(&cert)
+	s.logger.InfoContext(ctx, "SMTP TLS certificate reloaded",
+		"old_not_before", oldNotBefore,
+		"old_not_after", oldNotAfter,
+		"new_not_before", leaf.NotBefore,
+		"new_not_after", leaf.NotAfter,
+	)
+	return nil
+}
+
+// Start the listener and handle incoming connections.
+func (s *Server) Start(ctx context.Context, readyFunc func()) {
+	var listener, raw net.Listener
+	var err error
+	switch {
+	case s.config.LMTP && s.config.LMTPSocket != "":
+		listener, raw, err = listen("lmtp", "unix", s.config.LMTPSocket, s.config.ForceTLS, s.tlsConfig)
+		if err == nil {
+			s.logger.InfoContext(ctx, "LMTP listening on unix socket", "addr", s.config.LMTPSocket)
+		}
+	case s.config.LMTP:
+		network := s.config.Network
+		if network == "" {
+			network = "tcp4"
+		}
+		if err = requireLoopback(network, s.config.LMTPAddr, s.config.AllowInsecureLMTP); err != nil {
+			s.logger.ErrorContext(ctx, "refusing to start LMTP listener", "error", err)
+			s.notify <- err
+			close(s.notify)
+			return
+		}
+		listener, raw, err = listen("lmtp", network, s.config.LMTPAddr, s.config.ForceTLS, s.tlsConfig)
+		if err == nil {
+			s.logger.InfoContext(ctx, "LMTP listening", "addr", s.config.LMTPAddr, "network", network)
+		}
+	default:
+		network := s.config.Network
+		if network == "" {
+			network = "tcp"
+		}
+		listener, raw, err = listen("smtp", network, s.config.Addr, s.config.ForceTLS, s.tlsConfig)
+		if err == nil {
+			s.logger.InfoContext(ctx, "SMTP listening", "addr", s.config.Addr, "network", network)
+		}
 	}
 	if err != nil {
-		slog.Error().Err(err).Msg("Failed to start tcp4 listener")
+		s.logger.ErrorContext(ctx, "failed to start listener", "error", err)
 		s.notify <- err
 		close(s.notify)
 		return
 	}
+	s.listener = listener
+	s.rawListener = raw
 
 	// Start listener go routine.
 	go s.serve(ctx)
@@ -131,12 +220,11 @@ func (s *Server) Start(ctx context.Context, readyFunc func()) {
 
 	// Wait for shutdown.
 	<-ctx.Done()
-	slog = log.With().Str("module", "smtp").Str("phase", "shutdown").Logger()
-	slog.Debug().Msg("SMTP shutdown requested, connections will be drained")
+	s.logger.DebugContext(ctx, "SMTP shutdown requested, connections will be drained")
 
 	// Closing the listener will cause the serve() go routine to exit.
 	if err := s.listener.Close(); err != nil {
-		slog.Error().Err(err).Msg("Failed to close SMTP listener")
+		s.logger.ErrorContext(ctx, "failed to close SMTP listener", "error", err)
 	}
 }
 
@@ -157,8 +245,7 @@ func (s *Server) serve(ctx context.Context) {
 				if max := 1 * time.Second; tempDelay > max {
 					tempDelay = max
 				}
-				log.Error().Str("module", "smtp").Err(err).
-					Msgf("SMTP accept timeout; retrying in %v", tempDelay)
+				s.logger.ErrorContext(ctx, "SMTP accept timeout; retrying", "error", err, "retry_in", tempDelay)
 				time.Sleep(tempDelay)
 				continue
 			} else {
@@ -176,9 +263,29 @@ func (s *Server) serve(ctx context.Context) {
 			}
 		} else {
 			tempDelay = 0
-			expConnectsTotal.Add(1)
+			if s.config.LMTP {
+				expLMTPConnectsTotal.Add(1)
+			} else {
+				expConnectsTotal.Add(1)
+			}
+			if tcpConn, ok := tcpConnOf(conn); ok {
+				if err := s.applyKeepalive(tcpConn); err != nil {
+					s.logger.WarnContext(ctx, "failed to apply keepalive settings", "error", err)
+				}
+			} else {
+				s.logger.DebugContext(ctx, "skipping keepalive tuning for non-TCP connection")
+			}
+			if s.config.ProxyProtocol {
+				pconn, err := readProxyHeader(conn)
+				if err != nil {
+					s.logger.WarnContext(ctx, "failed to parse PROXY protocol header", "error", err)
+					conn.Close()
+					continue
+				}
+				conn = pconn
+			}
 			s.wg.Add(1)
-			go s.startSession(sessionID, conn, log.Logger)
+			go s.startSession(ctx, sessionID, conn)
 		}
 	}
 }
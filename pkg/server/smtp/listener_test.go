@@ -0,0 +1,132 @@
+package smtp
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log/slog"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/inbucket/inbucket/v3/pkg/config"
+)
+
+// writeSelfSignedCert writes a throwaway self-signed cert/key pair valid from
+// notBefore to certPath/keyPath, for exercising ReloadTLS without real certs.
+func writeSelfSignedCert(t *testing.T, certPath, keyPath string, notBefore time.Time) {
+	t.Helper()
+
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed generating key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "inbucket-test"},
+		NotBefore:    notBefore,
+		NotAfter:     notBefore.Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &priv.PublicKey, priv)
+	if err != nil {
+		t.Fatalf("failed creating certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(priv)
+	if err != nil {
+		t.Fatalf("failed marshaling key: %v", err)
+	}
+
+	certOut, err := os.Create(certPath)
+	if err != nil {
+		t.Fatalf("failed creating %s: %v", certPath, err)
+	}
+	defer certOut.Close()
+	if err := pem.Encode(certOut, &pem.Block{Type: "CERTIFICATE", Bytes: der}); err != nil {
+		t.Fatalf("failed writing cert: %v", err)
+	}
+
+	keyOut, err := os.Create(keyPath)
+	if err != nil {
+		t.Fatalf("failed creating %s: %v", keyPath, err)
+	}
+	defer keyOut.Close()
+	if err := pem.Encode(keyOut, &pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}); err != nil {
+		t.Fatalf("failed writing key: %v", err)
+	}
+}
+
+func newTestServer(t *testing.T, certPath, keyPath string) *Server {
+	t.Helper()
+	return &Server{
+		config: config.SMTP{TLSEnabled: true, TLSCert: certPath, TLSPrivKey: keyPath},
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+}
+
+func TestReloadTLSSwapsCertificate(t *testing.T) {
+	dir := t.TempDir()
+	certPath := filepath.Join(dir, "cert.pem")
+	keyPath := filepath.Join(dir, "key.pem")
+
+	oldNotBefore := time.Now().Add(-2 * time.Hour).Truncate(time.Second)
+	writeSelfSignedCert(t, certPath, keyPath, oldNotBefore)
+
+	s := newTestServer(t, certPath, keyPath)
+	initial, err := tls.LoadX509KeyPair(certPath, keyPath)
+	if err != nil {
+		t.Fatalf("failed loading initial keypair: %v", err)
+	}
+	s.tlsCert.Store(&initial)
+
+	newNotBefore := time.Now().Add(-time.Hour).Truncate(time.Second)
+	writeSelfSignedCert(t, certPath, keyPath, newNotBefore)
+
+	if err := s.ReloadTLS(); err != nil {
+		t.Fatalf("ReloadTLS() error = %v", err)
+	}
+
+	got, err := s.getCertificate(nil)
+	if err != nil {
+		t.Fatalf("getCertificate() error = %v", err)
+	}
+	leaf, err := x509.ParseCertificate(got.Certificate[0])
+	if err != nil {
+		t.Fatalf("failed parsing reloaded leaf: %v", err)
+	}
+	if !leaf.NotBefore.Equal(newNotBefore) {
+		t.Errorf("getCertificate() after reload NotBefore = %v, want %v", leaf.NotBefore, newNotBefore)
+	}
+}
+
+func TestReloadTLSDisabled(t *testing.T) {
+	s := &Server{
+		config: config.SMTP{TLSEnabled: false},
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	if err := s.ReloadTLS(); err != errTLSNotEnabled {
+		t.Errorf("ReloadTLS() with TLS disabled = %v, want errTLSNotEnabled", err)
+	}
+}
+
+func TestReloadTLSMissingFile(t *testing.T) {
+	dir := t.TempDir()
+	s := newTestServer(t, filepath.Join(dir, "missing-cert.pem"), filepath.Join(dir, "missing-key.pem"))
+
+	if err := s.ReloadTLS(); err == nil {
+		t.Error("ReloadTLS() with missing cert files = nil error, want failure")
+	}
+}
+
+func TestGetCertificateBeforeAnyLoad(t *testing.T) {
+	s := &Server{}
+	if _, err := s.getCertificate(nil); err != errNoCertificate {
+		t.Errorf("getCertificate() before any cert loaded = %v, want errNoCertificate", err)
+	}
+}
@@ -0,0 +1,115 @@
+package smtp
+
+import (
+	"expvar"
+	"fmt"
+	"io"
+	"net"
+)
+
+// Parallel stat collectors for LMTP mode, published in the "lmtp" expvar map under
+// the same names as the total/current counters in the "smtp" map (listener.go).
+// The *Hist series are kept for SMTP only.
+var (
+	expLMTPConnectsTotal   = new(expvar.Int)
+	expLMTPConnectsCurrent = new(expvar.Int)
+	expLMTPReceivedTotal   = new(expvar.Int)
+	expLMTPErrorsTotal     = new(expvar.Int)
+	expLMTPWarnsTotal      = new(expvar.Int)
+)
+
+func init() {
+	m := expvar.NewMap("lmtp")
+	m.Set("ConnectsTotal", expLMTPConnectsTotal)
+	m.Set("ConnectsCurrent", expLMTPConnectsCurrent)
+	m.Set("ReceivedTotal", expLMTPReceivedTotal)
+	m.Set("ErrorsTotal", expLMTPErrorsTotal)
+	m.Set("WarnsTotal", expLMTPWarnsTotal)
+}
+
+// RecipientStatus is the per-mailbox outcome of an LMTP DATA command, reported as
+// one reply line per accepted RCPT TO per RFC 2033 section 4.2.
+type RecipientStatus struct {
+	Recipient string
+	Delivered bool
+	Err       error
+}
+
+// deliverer is the minimal subset of message.Manager that deliverLMTP needs.
+// message.Manager satisfies it today; narrowing the parameter to just this
+// method lets the per-recipient delivery loop be unit tested without a full
+// message.Manager fake.
+type deliverer interface {
+	Deliver(mailbox, from string, to []string, body []byte) (id string, err error)
+}
+
+// deliverLMTP hands raw off to manager.Deliver once per recipient and collects a
+// RecipientStatus for each, so the LMTP session layer can emit the required
+// per-recipient reply line instead of SMTP's single aggregate response. Unlike
+// SMTP, a failure for one mailbox must not affect delivery to the others.
+func deliverLMTP(manager deliverer, recipients []*policyRecipient, raw io.Reader) ([]RecipientStatus, error) {
+	body, err := io.ReadAll(raw)
+	if err != nil {
+		return nil, fmt.Errorf("lmtp: failed to buffer message body: %w", err)
+	}
+
+	statuses := make([]RecipientStatus, 0, len(recipients))
+	for _, rcpt := range recipients {
+		_, err := manager.Deliver(rcpt.Mailbox, rcpt.From, rcpt.To, body)
+		statuses = append(statuses, RecipientStatus{
+			Recipient: rcpt.Original,
+			Delivered: err == nil,
+			Err:       err,
+		})
+	}
+	return statuses, nil
+}
+
+// writeLMTPReplies writes the RFC 2033 section 4.2 reply sequence for an LMTP
+// DATA command: one "250 2.0.0 <recipient> delivered" or "550 5.0.0 <recipient> <error>"
+// line per status, in the same order the recipients were accepted in.
+func writeLMTPReplies(w io.Writer, statuses []RecipientStatus) error {
+	for _, st := range statuses {
+		var line string
+		if st.Delivered {
+			line = fmt.Sprintf("250 2.0.0 <%s> delivered\r\n", st.Recipient)
+		} else {
+			line = fmt.Sprintf("550 5.0.0 <%s> %v\r\n", st.Recipient, st.Err)
+		}
+		if _, err := io.WriteString(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// policyRecipient is the minimal shape the LMTP delivery loop needs from an
+// accepted RCPT TO; the session layer builds one per recipient from
+// policy.Addressing before calling deliverLMTP.
+type policyRecipient struct {
+	Original string // Recipient exactly as sent in RCPT TO.
+	Mailbox  string // Resolved local mailbox name.
+	From     string
+	To       []string
+}
+
+// requireLoopback returns an error if addr is not a loopback or unix-domain
+// address and allowInsecure is false, per RFC 2033's requirement that LMTP not be
+// exposed on the public Internet.
+func requireLoopback(network, addr string, allowInsecure bool) error {
+	if allowInsecure || network == "unix" {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	ip := net.ParseIP(host)
+	if ip != nil && ip.IsLoopback() {
+		return nil
+	}
+	if host == "localhost" {
+		return nil
+	}
+	return fmt.Errorf("lmtp: refusing to bind non-loopback address %q without AllowInsecureLMTP", addr)
+}
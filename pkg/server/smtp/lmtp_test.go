@@ -0,0 +1,58 @@
+package smtp
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// fakeDeliverer implements deliverer, failing delivery for any mailbox listed
+// in fail.
+type fakeDeliverer struct {
+	fail map[string]bool
+}
+
+func (f *fakeDeliverer) Deliver(mailbox, from string, to []string, body []byte) (string, error) {
+	if f.fail[mailbox] {
+		return "", errors.New("mailbox over quota")
+	}
+	return "deadbeef", nil
+}
+
+func TestDeliverLMTPPerRecipientStatus(t *testing.T) {
+	manager := &fakeDeliverer{fail: map[string]bool{"bob": true}}
+	recipients := []*policyRecipient{
+		{Original: "alice@example.com", Mailbox: "alice", From: "sender@example.com", To: []string{"alice@example.com"}},
+		{Original: "bob@example.com", Mailbox: "bob", From: "sender@example.com", To: []string{"bob@example.com"}},
+	}
+
+	statuses, err := deliverLMTP(manager, recipients, strings.NewReader("Subject: test\r\n\r\nbody\r\n"))
+	if err != nil {
+		t.Fatalf("deliverLMTP returned error: %v", err)
+	}
+	if len(statuses) != len(recipients) {
+		t.Fatalf("got %d statuses, want %d", len(statuses), len(recipients))
+	}
+	if !statuses[0].Delivered {
+		t.Errorf("alice: got Delivered=false, want true")
+	}
+	if statuses[1].Delivered {
+		t.Errorf("bob: got Delivered=true, want false")
+	}
+
+	var buf bytes.Buffer
+	if err := writeLMTPReplies(&buf, statuses); err != nil {
+		t.Fatalf("writeLMTPReplies returned error: %v", err)
+	}
+	lines := strings.Split(strings.TrimRight(buf.String(), "\r\n"), "\r\n")
+	if len(lines) != len(recipients) {
+		t.Fatalf("got %d reply lines, want %d: %q", len(lines), len(recipients), buf.String())
+	}
+	if !strings.HasPrefix(lines[0], "250 2.0.0 <alice@example.com>") {
+		t.Errorf("line 0 = %q, want 250 reply for alice", lines[0])
+	}
+	if !strings.HasPrefix(lines[1], "550 5.0.0 <bob@example.com>") {
+		t.Errorf("line 1 = %q, want 550 reply for bob", lines[1])
+	}
+}
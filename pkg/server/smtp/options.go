@@ -0,0 +1,42 @@
+package smtp
+
+import (
+	"log/slog"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Option configures optional, non-default behavior on a Server created via
+// NewServer, following the same pattern as client.Option in
+// pkg/rest/client/apiv1_client_opts.go.
+type Option interface {
+	apply(*options)
+}
+
+type options struct {
+	logger *slog.Logger
+}
+
+// getDefaultOptions returns a logger backed by the same zerolog sink the process
+// already configures, so existing deployments see no log-format change unless
+// they opt in with WithLogger.
+func getDefaultOptions() *options {
+	return &options{
+		logger: slog.New(newZerologHandler(log.With().Str("module", "smtp").Logger())),
+	}
+}
+
+type loggerOption struct {
+	logger *slog.Logger
+}
+
+func (o loggerOption) apply(opts *options) {
+	opts.logger = o.logger
+}
+
+// WithLogger routes the server's log output through logger instead of the
+// default zerolog-backed handler, letting a host application fold inbucket's
+// logs into its own slog.Handler (journald, Stackdriver, OTel, etc.).
+func WithLogger(logger *slog.Logger) Option {
+	return loggerOption{logger}
+}
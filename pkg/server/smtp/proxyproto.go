@@ -0,0 +1,70 @@
+package smtp
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// proxyConn wraps a net.Conn whose PROXY protocol v1 header has already been
+// consumed, substituting the real upstream client address (as reported by the
+// proxy) for RemoteAddr so session logging reflects the original peer instead of
+// the proxy's own loopback address.
+type proxyConn struct {
+	net.Conn
+	remoteAddr net.Addr
+}
+
+func (c *proxyConn) RemoteAddr() net.Addr {
+	return c.remoteAddr
+}
+
+// readProxyHeader peeks at conn for a PROXY protocol v1 header ("PROXY TCP4 <src>
+// <dst> <srcport> <dstport>\r\n") as sent by nginx's proxy_protocol directive and,
+// if present, wraps conn so RemoteAddr reports the original client. If the
+// connection doesn't start with a PROXY header its bytes pass through unmodified:
+// conn is wrapped in a bufferedConn that replays what the bufio.Reader already
+// buffered, so nothing is lost.
+func readProxyHeader(conn net.Conn) (net.Conn, error) {
+	br := bufio.NewReader(conn)
+	peek, err := br.Peek(6)
+	if err != nil || string(peek) != "PROXY " {
+		// Not a PROXY header; hand back a conn that replays whatever bufio
+		// already buffered so the SMTP greeting isn't lost.
+		return &bufferedConn{Conn: conn, r: br}, nil
+	}
+
+	line, err := br.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("proxyproto: failed reading header: %w", err)
+	}
+	fields := strings.Fields(strings.TrimRight(line, "\r\n"))
+	// PROXY <proto> <src addr> <dst addr> <src port> <dst port>
+	if len(fields) != 6 {
+		return nil, fmt.Errorf("proxyproto: malformed header %q", line)
+	}
+	srcIP := fields[2]
+	srcPort, err := strconv.Atoi(fields[4])
+	if err != nil {
+		return nil, fmt.Errorf("proxyproto: invalid source port %q: %w", fields[4], err)
+	}
+
+	return &proxyConn{
+		Conn:       &bufferedConn{Conn: conn, r: br},
+		remoteAddr: &net.TCPAddr{IP: net.ParseIP(srcIP), Port: srcPort},
+	}, nil
+}
+
+// bufferedConn replays a bufio.Reader's buffered bytes ahead of further reads
+// from the underlying net.Conn, so peeking at the PROXY header doesn't drop the
+// bytes that follow it.
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.r.Read(p)
+}
@@ -0,0 +1,72 @@
+package smtp
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+)
+
+// fakeConn is a minimal net.Conn backed by a fixed byte slice, enough to drive
+// readProxyHeader without a real socket.
+type fakeConn struct {
+	net.Conn
+	r io.Reader
+}
+
+func (c *fakeConn) Read(p []byte) (int, error) {
+	return c.r.Read(p)
+}
+
+func TestReadProxyHeaderRewritesRemoteAddr(t *testing.T) {
+	raw := "PROXY TCP4 198.51.100.7 127.0.0.1 54321 25\r\nEHLO example.com\r\n"
+	conn := &fakeConn{r: bytes.NewReader([]byte(raw))}
+
+	wrapped, err := readProxyHeader(conn)
+	if err != nil {
+		t.Fatalf("readProxyHeader returned error: %v", err)
+	}
+
+	addr, ok := wrapped.RemoteAddr().(*net.TCPAddr)
+	if !ok {
+		t.Fatalf("RemoteAddr() = %T, want *net.TCPAddr", wrapped.RemoteAddr())
+	}
+	if addr.IP.String() != "198.51.100.7" || addr.Port != 54321 {
+		t.Errorf("RemoteAddr() = %v, want 198.51.100.7:54321", addr)
+	}
+
+	rest, err := io.ReadAll(wrapped)
+	if err != nil {
+		t.Fatalf("failed reading remainder: %v", err)
+	}
+	if string(rest) != "EHLO example.com\r\n" {
+		t.Errorf("remainder = %q, want the bytes following the PROXY header", rest)
+	}
+}
+
+func TestReadProxyHeaderPassesThroughNonProxyTraffic(t *testing.T) {
+	raw := "EHLO example.com\r\n"
+	conn := &fakeConn{r: bytes.NewReader([]byte(raw))}
+
+	wrapped, err := readProxyHeader(conn)
+	if err != nil {
+		t.Fatalf("readProxyHeader returned error: %v", err)
+	}
+
+	got, err := io.ReadAll(wrapped)
+	if err != nil {
+		t.Fatalf("failed reading: %v", err)
+	}
+	if string(got) != raw {
+		t.Errorf("got %q, want the original bytes %q unmodified", got, raw)
+	}
+}
+
+func TestReadProxyHeaderMalformed(t *testing.T) {
+	raw := "PROXY BOGUS\r\n"
+	conn := &fakeConn{r: bytes.NewReader([]byte(raw))}
+
+	if _, err := readProxyHeader(conn); err == nil {
+		t.Error("readProxyHeader with malformed header = nil error, want error")
+	}
+}
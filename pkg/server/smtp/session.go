@@ -0,0 +1,224 @@
+package smtp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"log/slog"
+	"net"
+	"strings"
+)
+
+// startSession drives a single accepted connection through the SMTP or LMTP
+// command sequence, depending on s.config.LMTP, until the client disconnects or
+// sends QUIT. ctx is serve's server-lifetime context, not a per-request one; it's
+// threaded through so session logging ties back to the server context instead of
+// a disconnected one.
+func (s *Server) startSession(ctx context.Context, id int, conn net.Conn) {
+	defer s.wg.Done()
+	// conn is reassigned on STARTTLS, so close whatever it holds at return time
+	// rather than the plain connection captured when the defer is registered.
+	defer func() { conn.Close() }()
+
+	logger := s.logger.With("session_id", id, "remote_addr", conn.RemoteAddr().String())
+
+	if s.config.LMTP {
+		expLMTPConnectsCurrent.Add(1)
+		defer expLMTPConnectsCurrent.Add(-1)
+	} else {
+		expConnectsCurrent.Add(1)
+		defer expConnectsCurrent.Add(-1)
+	}
+
+	reader := bufio.NewReader(conn)
+	writer := bufio.NewWriter(conn)
+
+	greeting := "220 inbucket ESMTP ready\r\n"
+	if s.config.LMTP {
+		greeting = "220 inbucket LMTP ready\r\n"
+	}
+	if _, err := writer.WriteString(greeting); err != nil {
+		logger.WarnContext(ctx, "failed writing greeting", "error", err)
+		return
+	}
+	writer.Flush()
+
+	var mailFrom string
+	var recipients []*policyRecipient
+
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd, arg := parseCommandLine(line)
+
+		switch cmd {
+		case "LHLO":
+			if !s.config.LMTP {
+				writer.WriteString("500 5.5.1 LHLO not permitted, use EHLO\r\n")
+				break
+			}
+			writer.WriteString("250 inbucket\r\n")
+		case "HELO", "EHLO":
+			if s.config.LMTP {
+				writer.WriteString("500 5.5.1 " + cmd + " not permitted, use LHLO\r\n")
+				break
+			}
+			writer.WriteString("250 inbucket\r\n")
+		case "STARTTLS":
+			if s.config.LMTP || s.config.ForceTLS {
+				writer.WriteString("503 5.5.1 STARTTLS not permitted here\r\n")
+				break
+			}
+			if !s.config.TLSEnabled {
+				writer.WriteString("502 5.5.1 STARTTLS not supported\r\n")
+				break
+			}
+			writer.WriteString("220 2.0.0 Ready to start TLS\r\n")
+			writer.Flush()
+			tlsConn := tls.Server(conn, s.tlsConfig)
+			if err := tlsConn.HandshakeContext(ctx); err != nil {
+				logger.WarnContext(ctx, "STARTTLS handshake failed", "error", err)
+				return
+			}
+			// Per RFC 3207 section 4.2, STARTTLS discards any prior transaction
+			// state and reader/writer buffers, since a subsequent MAIL FROM must be
+			// reissued over the encrypted channel.
+			conn = tlsConn
+			reader = bufio.NewReader(conn)
+			writer = bufio.NewWriter(conn)
+			mailFrom = ""
+			recipients = nil
+		case "MAIL":
+			mailFrom = extractAddr(arg)
+			writer.WriteString("250 2.1.0 OK\r\n")
+		case "RCPT":
+			addr := extractAddr(arg)
+			mailbox, err := s.addrPolicy.ExtractMailbox(addr)
+			if err != nil {
+				writer.WriteString("550 5.1.1 " + addr + " unknown\r\n")
+				break
+			}
+			recipients = append(recipients, &policyRecipient{
+				Original: addr,
+				Mailbox:  mailbox,
+				From:     mailFrom,
+				To:       []string{addr},
+			})
+			writer.WriteString("250 2.1.5 OK\r\n")
+		case "DATA":
+			s.handleData(ctx, writer, reader, mailFrom, recipients, logger)
+			mailFrom = ""
+			recipients = nil
+		case "RSET":
+			mailFrom = ""
+			recipients = nil
+			writer.WriteString("250 2.0.0 OK\r\n")
+		case "NOOP":
+			writer.WriteString("250 2.0.0 OK\r\n")
+		case "QUIT":
+			writer.WriteString("221 2.0.0 Bye\r\n")
+			writer.Flush()
+			return
+		default:
+			writer.WriteString("500 5.5.1 Unrecognized command\r\n")
+		}
+		if err := writer.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// handleData reads the dot-terminated message body and replies per RFC 5321 (one
+// aggregate "250 OK") or, in LMTP mode, per RFC 2033 (one status line per accepted
+// recipient), delivering into every accepted recipient's mailbox either way.
+func (s *Server) handleData(
+	ctx context.Context,
+	writer *bufio.Writer,
+	reader *bufio.Reader,
+	mailFrom string,
+	recipients []*policyRecipient,
+	logger *slog.Logger,
+) {
+	if len(recipients) == 0 {
+		writer.WriteString("503 5.5.1 RCPT TO required before DATA\r\n")
+		return
+	}
+	writer.WriteString("354 Start mail input; end with <CRLF>.<CRLF>\r\n")
+	writer.Flush()
+
+	body, err := readDotTerminated(reader)
+	if err != nil {
+		writer.WriteString("451 4.3.0 failed reading message body\r\n")
+		return
+	}
+
+	if s.config.LMTP {
+		expLMTPReceivedTotal.Add(1)
+		statuses, err := deliverLMTP(s.manager, recipients, bytes.NewReader(body))
+		if err != nil {
+			expLMTPErrorsTotal.Add(1)
+			logger.ErrorContext(ctx, "lmtp delivery failed", "error", err)
+			writer.WriteString("451 4.3.0 " + err.Error() + "\r\n")
+			return
+		}
+		writeLMTPReplies(writer, statuses)
+		return
+	}
+
+	expReceivedTotal.Add(1)
+	to := make([]string, 0, len(recipients))
+	for _, r := range recipients {
+		to = append(to, r.Original)
+	}
+	for _, r := range recipients {
+		if _, err := s.manager.Deliver(r.Mailbox, mailFrom, to, body); err != nil {
+			expErrorsTotal.Add(1)
+			logger.ErrorContext(ctx, "smtp delivery failed", "mailbox", r.Mailbox, "error", err)
+			writer.WriteString("451 4.3.0 " + err.Error() + "\r\n")
+			return
+		}
+	}
+	writer.WriteString("250 2.0.0 OK: message accepted\r\n")
+}
+
+// parseCommandLine splits a command line into its verb (upper-cased) and
+// argument, trimming the trailing CRLF.
+func parseCommandLine(line string) (cmd, arg string) {
+	line = strings.TrimRight(line, "\r\n")
+	cmd, arg, _ = strings.Cut(line, " ")
+	return strings.ToUpper(cmd), arg
+}
+
+// extractAddr pulls the address out of a MAIL FROM:<addr> / RCPT TO:<addr>
+// argument, tolerating the angle brackets being absent.
+func extractAddr(arg string) string {
+	_, addr, found := strings.Cut(arg, ":")
+	if !found {
+		addr = arg
+	}
+	addr = strings.TrimSpace(addr)
+	addr = strings.TrimPrefix(addr, "<")
+	addr = strings.TrimSuffix(addr, ">")
+	return addr
+}
+
+// readDotTerminated reads lines until a lone "." terminator, per RFC 5321
+// section 4.1.1.4, returning the accumulated body without the terminator. Any
+// leading "." a client stuffed onto a body line to escape it (section 4.5.2)
+// is undone before the line is appended.
+func readDotTerminated(reader *bufio.Reader) ([]byte, error) {
+	var body bytes.Buffer
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		if strings.TrimRight(line, "\r\n") == "." {
+			return body.Bytes(), nil
+		}
+		body.WriteString(strings.TrimPrefix(line, "."))
+	}
+}
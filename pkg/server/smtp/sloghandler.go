@@ -0,0 +1,84 @@
+package smtp
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/rs/zerolog"
+)
+
+// zerologHandler adapts a zerolog.Logger to the slog.Handler interface, so
+// packages that migrate their call sites to log/slog keep writing to the same
+// sink the host process already configured, unless the caller opts into their
+// own slog.Handler via WithLogger.
+type zerologHandler struct {
+	logger zerolog.Logger
+	attrs  []slog.Attr
+	group  string
+}
+
+func newZerologHandler(logger zerolog.Logger) *zerologHandler {
+	return &zerologHandler{logger: logger}
+}
+
+func (h *zerologHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.logger.GetLevel() <= zerologLevel(level)
+}
+
+func (h *zerologHandler) Handle(_ context.Context, r slog.Record) error {
+	event := zerologEvent(h.logger, zerologLevel(r.Level))
+	for _, a := range h.attrs {
+		event = event.Interface(h.prefixed(a.Key), a.Value.Any())
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		event = event.Interface(h.prefixed(a.Key), a.Value.Any())
+		return true
+	})
+	event.Msg(r.Message)
+	return nil
+}
+
+func (h *zerologHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &zerologHandler{logger: h.logger, attrs: append(append([]slog.Attr{}, h.attrs...), attrs...), group: h.group}
+}
+
+func (h *zerologHandler) WithGroup(name string) slog.Handler {
+	group := name
+	if h.group != "" {
+		group = h.group + "." + name
+	}
+	return &zerologHandler{logger: h.logger, attrs: h.attrs, group: group}
+}
+
+func (h *zerologHandler) prefixed(key string) string {
+	if h.group == "" {
+		return key
+	}
+	return h.group + "." + key
+}
+
+func zerologLevel(level slog.Level) zerolog.Level {
+	switch {
+	case level >= slog.LevelError:
+		return zerolog.ErrorLevel
+	case level >= slog.LevelWarn:
+		return zerolog.WarnLevel
+	case level >= slog.LevelInfo:
+		return zerolog.InfoLevel
+	default:
+		return zerolog.DebugLevel
+	}
+}
+
+func zerologEvent(logger zerolog.Logger, level zerolog.Level) *zerolog.Event {
+	switch level {
+	case zerolog.ErrorLevel:
+		return logger.Error()
+	case zerolog.WarnLevel:
+		return logger.Warn()
+	case zerolog.InfoLevel:
+		return logger.Info()
+	default:
+		return logger.Debug()
+	}
+}
@@ -0,0 +1,59 @@
+package smtp
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func TestZerologHandlerAttrsAndMessage(t *testing.T) {
+	var buf bytes.Buffer
+	h := newZerologHandler(zerolog.New(&buf))
+
+	logger := slog.New(h)
+	logger.Info("hello", "key", "value")
+
+	got := buf.String()
+	if !bytes.Contains([]byte(got), []byte(`"key":"value"`)) {
+		t.Errorf("log output missing attr, got: %s", got)
+	}
+	if !bytes.Contains([]byte(got), []byte(`"message":"hello"`)) {
+		t.Errorf("log output missing message, got: %s", got)
+	}
+}
+
+func TestZerologHandlerWithGroupNests(t *testing.T) {
+	var buf bytes.Buffer
+	h := newZerologHandler(zerolog.New(&buf))
+
+	nested := h.WithGroup("a").WithGroup("b").WithAttrs([]slog.Attr{slog.String("key", "value")})
+	record := slog.NewRecord(time.Time{}, slog.LevelInfo, "msg", 0)
+	if err := nested.Handle(context.Background(), record); err != nil {
+		t.Fatalf("Handle() error = %v", err)
+	}
+
+	got := buf.String()
+	if !bytes.Contains([]byte(got), []byte(`"a.b.key":"value"`)) {
+		t.Errorf(`WithGroup("a").WithGroup("b") should prefix keys with "a.b.", got: %s`, got)
+	}
+}
+
+func TestZerologHandlerWithAttrsPreservesGroup(t *testing.T) {
+	var buf bytes.Buffer
+	h := newZerologHandler(zerolog.New(&buf))
+
+	grouped := h.WithGroup("req").WithAttrs([]slog.Attr{slog.String("id", "123")})
+	record := slog.NewRecord(time.Time{}, slog.LevelInfo, "msg", 0)
+	if err := grouped.Handle(context.Background(), record); err != nil {
+		t.Fatalf("Handle() error = %v", err)
+	}
+
+	got := buf.String()
+	if !bytes.Contains([]byte(got), []byte(`"req.id":"123"`)) {
+		t.Errorf("WithAttrs after WithGroup should prefix with group, got: %s", got)
+	}
+}